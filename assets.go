@@ -0,0 +1,88 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import "sync"
+
+// meshKey caches a mesh variant by its base name and subdivision
+// level so repeated SetSubdivisions calls with the same level share
+// one smoothed mesh rather than re-subdividing every time.
+type meshKey struct {
+	name         string
+	subdivisions int
+}
+
+// assetCache holds loaded base meshes and materials and any derived
+// mesh variants, eg: the subdivided meshes used by the "subsurf" role.
+type assetCache struct {
+	mu        sync.Mutex
+	meshes    map[string]*Mesh
+	resolved  map[meshKey]*Mesh
+	materials map[string]bool
+}
+
+// assets is the engine's single asset cache.
+var assets = &assetCache{
+	meshes:    map[string]*Mesh{},
+	resolved:  map[meshKey]*Mesh{},
+	materials: map[string]bool{},
+}
+
+// RegisterMesh adds or replaces a base mesh, available afterwards by
+// name to Role.SetMesh and Role.SetSubdivisions, and as a resolvable
+// name when loading a saved scene with Engine.LoadScene.
+func RegisterMesh(m *Mesh) { assets.registerMesh(m) }
+
+// RegisterMaterial declares name as a known material, available
+// afterwards as a resolvable name to Role.SetMaterial and when loading
+// a saved scene with Engine.LoadScene.
+func RegisterMaterial(name string) { assets.registerMaterial(name) }
+
+// registerMesh adds or replaces a base mesh, available afterwards by
+// name to SetMesh and SetSubdivisions.
+func (c *assetCache) registerMesh(m *Mesh) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.meshes[m.name] = m
+	for k := range c.resolved {
+		if k.name == m.name {
+			delete(c.resolved, k)
+		}
+	}
+}
+
+// registerMaterial declares name as a known material.
+func (c *assetCache) registerMaterial(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.materials[name] = true
+}
+
+// hasMaterial reports whether name was registered with registerMaterial.
+func (c *assetCache) hasMaterial(name string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.materials[name]
+}
+
+// resolveMesh returns the named base mesh, Loop-subdivided subdivisions
+// times, building and caching it on first request.
+func (c *assetCache) resolveMesh(name string, subdivisions int) (*Mesh, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := meshKey{name, subdivisions}
+	if m, ok := c.resolved[key]; ok {
+		return m, true
+	}
+	base, ok := c.meshes[name]
+	if !ok {
+		return nil, false
+	}
+	m := base
+	for i := 0; i < subdivisions; i++ {
+		m = loopSubdivide(m)
+	}
+	c.resolved[key] = m
+	return m, true
+}
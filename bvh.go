@@ -0,0 +1,321 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import "math"
+
+// bvhBins is the number of uniform bins used when choosing a binned
+// SAH split along the longest axis of a node's centroids.
+const bvhBins = 16
+
+// bvhLeafMax is the largest number of parts a BVH leaf may hold
+// before it must be split further.
+const bvhLeafMax = 4
+
+// bvhRebuildRatio is how much a refit tree's SAH cost may grow over
+// its last full-build cost before a rebuild is triggered.
+const bvhRebuildRatio = 1.3
+
+// aabb is an axis aligned bounding box.
+type aabb struct{ min, max [3]float64 }
+
+func emptyAABB() aabb {
+	inf := math.Inf(1)
+	return aabb{min: [3]float64{inf, inf, inf}, max: [3]float64{-inf, -inf, -inf}}
+}
+
+func unionAABB(a, b aabb) aabb {
+	u := aabb{}
+	for i := 0; i < 3; i++ {
+		u.min[i] = math.Min(a.min[i], b.min[i])
+		u.max[i] = math.Max(a.max[i], b.max[i])
+	}
+	return u
+}
+
+func unionRange(aabbs []aabb, idxs []int) aabb {
+	u := emptyAABB()
+	for _, i := range idxs {
+		u = unionAABB(u, aabbs[i])
+	}
+	return u
+}
+
+func (a aabb) centroid() [3]float64 {
+	return [3]float64{(a.min[0] + a.max[0]) * 0.5, (a.min[1] + a.max[1]) * 0.5, (a.min[2] + a.max[2]) * 0.5}
+}
+
+func (a aabb) area() float64 {
+	d := [3]float64{a.max[0] - a.min[0], a.max[1] - a.min[1], a.max[2] - a.min[2]}
+	return 2 * (d[0]*d[1] + d[1]*d[2] + d[2]*d[0])
+}
+
+func (a aabb) longestAxis() int {
+	d := [3]float64{a.max[0] - a.min[0], a.max[1] - a.min[1], a.max[2] - a.min[2]}
+	axis := 0
+	if d[1] > d[axis] {
+		axis = 1
+	}
+	if d[2] > d[axis] {
+		axis = 2
+	}
+	return axis
+}
+
+// bvhNode is one node of the tree. Interior nodes have left/right
+// child indices into the owning bvh's nodes slice; leaves instead
+// reference a run of bvh.order.
+type bvhNode struct {
+	box         aabb
+	left, right int // -1 for a leaf.
+	start, count int
+}
+
+// bvh is a binned-SAH bounding volume hierarchy over a scene's parts,
+// used to cull parts outside the camera frustum before draw
+// submission.
+type bvh struct {
+	parts []*part
+	order []int
+	nodes []bvhNode
+
+	cost             float64 // SAH cost as of the last full build.
+	refits, rebuilds int
+}
+
+func newBVH() *bvh { return &bvh{} }
+
+func (b *bvh) nodeCount() int { return len(b.nodes) }
+
+func (b *bvh) leafCount() int {
+	n := 0
+	for _, nd := range b.nodes {
+		if nd.left < 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// sync brings the BVH up to date with the scene's current parts. A
+// change in the part set - including a same-count swap, eg: one part
+// removed and a different one added the same frame - forces a full
+// rebuild; otherwise the existing tree is refit in place and only
+// rebuilt if its SAH cost has grown too far past its last full-build
+// cost.
+func (b *bvh) sync(parts []*part) {
+	if b.nodes == nil || !samePartList(b.parts, parts) {
+		b.build(parts)
+		return
+	}
+	b.parts = parts
+	aabbs := b.currentAABBs()
+	b.refit(aabbs)
+	if b.cost > 0 && b.sahCost() > b.cost*bvhRebuildRatio {
+		b.build(parts)
+		return
+	}
+	b.refits++
+}
+
+// samePartList reports whether a and b hold the same parts in the
+// same order. A length-only check would miss a same-count swap, which
+// would otherwise be silently refit against a split computed for
+// parts that no longer exist.
+func samePartList(a, b []*part) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i, p := range a {
+		if p != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bvh) currentAABBs() []aabb {
+	aabbs := make([]aabb, len(b.parts))
+	for i, p := range b.parts {
+		aabbs[i] = p.worldAABB()
+	}
+	return aabbs
+}
+
+// build performs a full top-down binned-SAH build over parts.
+func (b *bvh) build(parts []*part) {
+	b.parts = parts
+	n := len(parts)
+	aabbs := b.currentAABBs()
+	b.order = make([]int, n)
+	for i := range b.order {
+		b.order[i] = i
+	}
+	b.nodes = b.nodes[:0]
+	if n > 0 {
+		b.buildRange(aabbs, 0, n)
+	}
+	b.cost = b.sahCost()
+	b.rebuilds++
+}
+
+// buildRange builds the subtree covering b.order[lo:hi] and returns
+// its node index.
+func (b *bvh) buildRange(aabbs []aabb, lo, hi int) int {
+	idx := len(b.nodes)
+	b.nodes = append(b.nodes, bvhNode{})
+	box := unionRange(aabbs, b.order[lo:hi])
+	if hi-lo <= bvhLeafMax {
+		b.nodes[idx] = bvhNode{box: box, left: -1, right: -1, start: lo, count: hi - lo}
+		return idx
+	}
+	split := b.binnedSplit(aabbs, lo, hi, box)
+	if split <= lo || split >= hi {
+		split = (lo + hi) / 2 // degenerate bins (eg: coincident centroids): fall back to a median split.
+	}
+	left := b.buildRange(aabbs, lo, split)
+	right := b.buildRange(aabbs, split, hi)
+	b.nodes[idx] = bvhNode{box: box, left: left, right: right}
+	return idx
+}
+
+// binnedSplit projects [lo:hi)'s centroids onto box's longest axis,
+// bins them into bvhBins uniform buckets, and picks the bucket
+// boundary minimizing Aleft*Nleft + Aright*Nright. It partitions
+// b.order[lo:hi) in place to match the chosen boundary and returns the
+// split point.
+func (b *bvh) binnedSplit(aabbs []aabb, lo, hi int, box aabb) int {
+	axis := box.longestAxis()
+	cmin, cmax := math.Inf(1), math.Inf(-1)
+	for i := lo; i < hi; i++ {
+		c := aabbs[b.order[i]].centroid()[axis]
+		cmin, cmax = math.Min(cmin, c), math.Max(cmax, c)
+	}
+	if cmax-cmin < 1e-9 {
+		return (lo + hi) / 2
+	}
+	scale := float64(bvhBins) / (cmax - cmin)
+	binOf := func(c float64) int {
+		bi := int((c - cmin) * scale)
+		if bi >= bvhBins {
+			bi = bvhBins - 1
+		}
+		if bi < 0 {
+			bi = 0
+		}
+		return bi
+	}
+
+	counts := [bvhBins]int{}
+	boxes := [bvhBins]aabb{}
+	for i := range boxes {
+		boxes[i] = emptyAABB()
+	}
+	for i := lo; i < hi; i++ {
+		idx := b.order[i]
+		bi := binOf(aabbs[idx].centroid()[axis])
+		counts[bi]++
+		boxes[bi] = unionAABB(boxes[bi], aabbs[idx])
+	}
+
+	var prefixArea, suffixArea [bvhBins + 1]float64
+	var prefixCount, suffixCount [bvhBins + 1]int
+	running, runCount := emptyAABB(), 0
+	for i := 0; i < bvhBins; i++ {
+		if counts[i] > 0 {
+			running = unionAABB(running, boxes[i])
+		}
+		runCount += counts[i]
+		prefixArea[i+1], prefixCount[i+1] = running.area(), runCount
+	}
+	running, runCount = emptyAABB(), 0
+	for i := bvhBins - 1; i >= 0; i-- {
+		if counts[i] > 0 {
+			running = unionAABB(running, boxes[i])
+		}
+		runCount += counts[i]
+		suffixArea[i], suffixCount[i] = running.area(), runCount
+	}
+
+	bestCost, bestBin := math.Inf(1), -1
+	for i := 1; i < bvhBins; i++ {
+		if prefixCount[i] == 0 || suffixCount[i] == 0 {
+			continue
+		}
+		cost := prefixArea[i]*float64(prefixCount[i]) + suffixArea[i]*float64(suffixCount[i])
+		if cost < bestCost {
+			bestCost, bestBin = cost, i
+		}
+	}
+	if bestBin < 0 {
+		return (lo + hi) / 2
+	}
+
+	// partition b.order[lo:hi) so indices whose bin < bestBin come first.
+	i, j := lo, hi-1
+	for i <= j {
+		for i <= j && binOf(aabbs[b.order[i]].centroid()[axis]) < bestBin {
+			i++
+		}
+		for i <= j && binOf(aabbs[b.order[j]].centroid()[axis]) >= bestBin {
+			j--
+		}
+		if i < j {
+			b.order[i], b.order[j] = b.order[j], b.order[i]
+			i++
+			j--
+		}
+	}
+	return i
+}
+
+// refit recomputes every node's box bottom-up without changing the
+// tree's shape. A node's slot is always appended before its children's
+// during build, so a simple reverse scan visits children before parents.
+func (b *bvh) refit(aabbs []aabb) {
+	for i := len(b.nodes) - 1; i >= 0; i-- {
+		nd := &b.nodes[i]
+		if nd.left < 0 {
+			nd.box = unionRange(aabbs, b.order[nd.start:nd.start+nd.count])
+		} else {
+			nd.box = unionAABB(b.nodes[nd.left].box, b.nodes[nd.right].box)
+		}
+	}
+}
+
+// sahCost sums the surface area of every node's current box, used as
+// a cheap proxy for the tree's overall SAH quality: it grows as refits
+// let the bounds loosen around parts that have moved.
+func (b *bvh) sahCost() float64 {
+	cost := 0.0
+	for _, nd := range b.nodes {
+		cost += nd.box.area()
+	}
+	return cost
+}
+
+// query returns the parts in leaves whose box intersects f.
+func (b *bvh) query(f *frustum) []*part {
+	if len(b.nodes) == 0 {
+		return nil
+	}
+	var out []*part
+	var walk func(i int)
+	walk = func(i int) {
+		nd := b.nodes[i]
+		if !f.Intersects(nd.box.min, nd.box.max) {
+			return
+		}
+		if nd.left < 0 {
+			for _, oi := range b.order[nd.start : nd.start+nd.count] {
+				out = append(out, b.parts[oi])
+			}
+			return
+		}
+		walk(nd.left)
+		walk(nd.right)
+	}
+	walk(0)
+	return out
+}
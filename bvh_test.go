@@ -0,0 +1,74 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import "testing"
+
+func newTestPart(x, y, z float64) *part {
+	p := newPart(nil)
+	p.SetLocation(x, y, z)
+	return p
+}
+
+// TestBVHSyncRebuildsOnPartSwap checks the fix for a same-count part
+// swap: replacing one part with a different one at the same index must
+// not be mistaken for an unchanged part list.
+func TestBVHSyncRebuildsOnPartSwap(t *testing.T) {
+	parts := []*part{newTestPart(0, 0, 0), newTestPart(10, 0, 0)}
+	b := newBVH()
+	b.sync(parts)
+	rebuildsAfterFirstSync := b.rebuilds
+
+	swapped := []*part{parts[0], newTestPart(-10, 0, 0)}
+	b.sync(swapped)
+	if b.rebuilds != rebuildsAfterFirstSync+1 {
+		t.Fatalf("rebuilds = %d, want %d after a same-count part swap", b.rebuilds, rebuildsAfterFirstSync+1)
+	}
+	box := b.nodes[0].box
+	if box.min[0] > -10 || box.max[0] < 0 {
+		t.Fatalf("root box %v doesn't cover the swapped-in part's location", box)
+	}
+}
+
+// TestBVHSyncRefitsUnchangedPartList checks that an unchanged part list
+// is refit in place rather than rebuilt.
+func TestBVHSyncRefitsUnchangedPartList(t *testing.T) {
+	parts := []*part{newTestPart(0, 0, 0), newTestPart(10, 0, 0)}
+	b := newBVH()
+	b.sync(parts)
+	rebuilds := b.rebuilds
+
+	b.sync(parts)
+	if b.rebuilds != rebuilds {
+		t.Fatalf("rebuilds = %d, want unchanged at %d for the same part list", b.rebuilds, rebuilds)
+	}
+	if b.refits == 0 {
+		t.Fatalf("expected a refit to have been recorded")
+	}
+}
+
+// TestSceneCullSkipsBVHSyncWhenNothingChanged exercises the event
+// driven path: cull should not resync the BVH on a frame where nothing
+// was added, removed, or moved.
+func TestSceneCullSkipsBVHSyncWhenNothingChanged(t *testing.T) {
+	s := newScene()
+	s.AddPart().SetLocation(0, 0, 0)
+	s.AddPart().SetLocation(10, 0, 0)
+	s.EnableBVH(true)
+	s.cull()
+	rebuilds, refits := s.bvh.rebuilds, s.bvh.refits
+
+	s.cull()
+	if s.bvh.rebuilds != rebuilds || s.bvh.refits != refits {
+		t.Fatalf("cull resynced the BVH on an unchanged frame: rebuilds %d->%d refits %d->%d",
+			rebuilds, s.bvh.rebuilds, refits, s.bvh.refits)
+	}
+
+	// Moving a part must mark the scene dirty so the next cull resyncs.
+	s.parts[0].SetLocation(1, 1, 1)
+	s.cull()
+	if s.bvh.refits == refits && s.bvh.rebuilds == rebuilds {
+		t.Fatalf("cull didn't resync the BVH after a part moved")
+	}
+}
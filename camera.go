@@ -0,0 +1,100 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Camera controls a scene's view and projection.
+type Camera interface {
+	SetPerspective(fov, aspect, near, far float64) Camera
+	SetLocation(x, y, z float64) Camera
+	Move(x, y, z float64)
+}
+
+// plane is ax+by+cz+d=0 with (a,b,c) a unit normal pointing into the
+// visible half-space.
+type plane struct{ a, b, c, d float64 }
+
+func (p plane) dist(x, y, z float64) float64 { return p.a*x + p.b*y + p.c*z + p.d }
+
+// frustum is the 6 planes (left, right, top, bottom, near, far) of a
+// camera's view volume, used to cull AABBs that can't be seen.
+type frustum struct{ planes [6]plane }
+
+// Intersects reports whether the AABB given by min,max is at least
+// partially inside f. It rejects only on a definite separating plane,
+// so it may report false positives near the boundary but never a
+// false negative.
+func (f *frustum) Intersects(min, max [3]float64) bool {
+	for _, p := range f.planes {
+		// the AABB corner most likely to be inside, given the plane normal.
+		px, py, pz := min[0], min[1], min[2]
+		if p.a >= 0 {
+			px = max[0]
+		}
+		if p.b >= 0 {
+			py = max[1]
+		}
+		if p.c >= 0 {
+			pz = max[2]
+		}
+		if p.dist(px, py, pz) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// camera is the default Camera implementation.
+type camera struct {
+	loc         *lin.V3
+	fov, aspect float64
+	near, far   float64
+}
+
+func newCamera() *camera {
+	return &camera{loc: &lin.V3{}, fov: 60, aspect: 1, near: 0.1, far: 100}
+}
+
+func (c *camera) SetPerspective(fov, aspect, near, far float64) Camera {
+	c.fov, c.aspect, c.near, c.far = fov, aspect, near, far
+	return c
+}
+
+func (c *camera) SetLocation(x, y, z float64) Camera {
+	c.loc = &lin.V3{x, y, z}
+	return c
+}
+
+func (c *camera) Move(x, y, z float64) {
+	c.loc.X += x
+	c.loc.Y += y
+	c.loc.Z += z
+}
+
+// frustum builds the current view frustum looking down -Z from the
+// camera's location, used by Scene's BVH to cull parts before draw.
+func (c *camera) frustum() *frustum {
+	halfV := math.Tan(c.fov * math.Pi / 180 * 0.5)
+	halfH := halfV * c.aspect
+	x, y, z := c.loc.X, c.loc.Y, c.loc.Z
+	f := &frustum{}
+	// near and far are axis aligned along -Z from the camera.
+	f.planes[4] = plane{a: 0, b: 0, c: -1, d: z - c.near}
+	f.planes[5] = plane{a: 0, b: 0, c: 1, d: -(z - c.far)}
+	// side planes, unnormalized normals are fine since Intersects only checks sign.
+	nx := math.Cos(math.Atan(halfH))
+	nz := -math.Sin(math.Atan(halfH)) // inward normals tilt toward -Z, the view direction.
+	f.planes[0] = plane{a: nx, b: 0, c: nz, d: -(nx*x + nz*z)}  // left
+	f.planes[1] = plane{a: -nx, b: 0, c: nz, d: -(-nx*x + nz*z)} // right
+	ny := math.Cos(math.Atan(halfV))
+	nzv := -math.Sin(math.Atan(halfV))
+	f.planes[2] = plane{a: 0, b: -ny, c: nzv, d: -(-ny*y + nzv*z)} // top
+	f.planes[3] = plane{a: 0, b: ny, c: nzv, d: -(ny*y + nzv*z)}   // bottom
+	return f
+}
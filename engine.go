@@ -0,0 +1,78 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import "io"
+
+// Engine is the top level handle to a running application: its
+// window, scenes, and per-frame update loop.
+type Engine interface {
+	SetDirector(d Director)
+	AddScene(vp int) Scene
+	Enable(flag int, on bool)
+	Color(r, g, b, a float64)
+	Action() // run one cull/update pass over every scene; it does not loop until Shutdown.
+	Shutdown()
+	Size() (x, y, width, height int)
+	Resize(x, y, width, height int)
+
+	// LoadScene reconstructs a scene previously written by Scene.Save,
+	// adding it to the engine. It returns a descriptive error naming any
+	// mesh/material that fails to resolve rather than loading a scene
+	// that silently draws nothing.
+	LoadScene(r io.Reader) (Scene, error)
+}
+
+// engine is the default Engine implementation.
+type engine struct {
+	title               string
+	x, y, width, height int
+	scenes              []*scene
+	director            Director
+}
+
+// New creates and opens an application window.
+func New(title string, x, y, width, height int) (Engine, error) {
+	return &engine{title: title, x: x, y: y, width: width, height: height}, nil
+}
+
+func (e *engine) SetDirector(d Director) { e.director = d }
+
+func (e *engine) AddScene(vp int) Scene {
+	s := newScene()
+	s.is2D = vp != VP
+	e.scenes = append(e.scenes, s)
+	return s
+}
+
+func (e *engine) Enable(flag int, on bool) {}
+func (e *engine) Color(r, g, b, a float64) {}
+
+// Action runs one update/cull pass: it culls every scene against its
+// camera's frustum (syncing each scene's BVH first if needed) and then
+// lets the director react to input. A real per-frame render loop needs
+// a window/GL binding this package doesn't provide yet; this is the
+// seam a full implementation would loop.
+func (e *engine) Action() {
+	for _, s := range e.scenes {
+		s.cull()
+	}
+	if e.director != nil {
+		e.director.Update(&Input{Down: map[string]int{}})
+	}
+}
+func (e *engine) Shutdown() {}
+func (e *engine) Size() (int, int, int, int)  { return e.x, e.y, e.width, e.height }
+func (e *engine) Resize(x, y, w, h int) {
+	e.x, e.y, e.width, e.height = x, y, w, h
+}
+
+func (e *engine) LoadScene(r io.Reader) (Scene, error) {
+	s, err := loadScene(r, BinaryCodec)
+	if err != nil {
+		return nil, err
+	}
+	e.scenes = append(e.scenes, s)
+	return s, nil
+}
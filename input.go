@@ -0,0 +1,29 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Input is the per-frame snapshot of user input handed to a
+// Director's Update.
+type Input struct {
+	Dt      float64        // seconds since the last update.
+	Resized bool           // true the frame the window was resized.
+	Down    map[string]int // keys/buttons currently down, value is how many frames.
+}
+
+// Director receives per-frame input and drives application state.
+type Director interface {
+	Update(in *Input)
+}
+
+// Render/blend/cull flags for Engine.Enable.
+const (
+	BLEND = iota
+	CULL
+)
+
+// Viewport/projection kinds for Engine.AddScene.
+const (
+	VP  = iota // 3D perspective viewport.
+	VPOrtho
+)
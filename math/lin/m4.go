@@ -0,0 +1,120 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package lin
+
+import "math"
+
+// M4 is a 4x4 affine transform matrix stored as a 3x3 basis (rotation
+// and scale) plus a translation. The bottom row is always (0,0,0,1)
+// since vu never needs projective transforms on part matrices.
+type M4 struct {
+	Xx, Xy, Xz float64
+	Yx, Yy, Yz float64
+	Zx, Zy, Zz float64
+	Tx, Ty, Tz float64
+}
+
+// M4I returns the identity matrix.
+func M4I() *M4 { return &M4{Xx: 1, Yy: 1, Zz: 1} }
+
+// NewTRS builds a matrix from a translation, rotation and scale.
+func NewTRS(loc *V3, rot *Q, scale *V3) *M4 {
+	m := rot.M4()
+	m.Xx, m.Xy, m.Xz = m.Xx*scale.X, m.Xy*scale.X, m.Xz*scale.X
+	m.Yx, m.Yy, m.Yz = m.Yx*scale.Y, m.Yy*scale.Y, m.Yz*scale.Y
+	m.Zx, m.Zy, m.Zz = m.Zx*scale.Z, m.Zy*scale.Z, m.Zz*scale.Z
+	m.Tx, m.Ty, m.Tz = loc.X, loc.Y, loc.Z
+	return m
+}
+
+// Mult returns m*m2, ie: m2 applied first, as a new matrix.
+func (m *M4) Mult(m2 *M4) *M4 {
+	return &M4{
+		Xx: m.Xx*m2.Xx + m.Yx*m2.Xy + m.Zx*m2.Xz,
+		Xy: m.Xy*m2.Xx + m.Yy*m2.Xy + m.Zy*m2.Xz,
+		Xz: m.Xz*m2.Xx + m.Yz*m2.Xy + m.Zz*m2.Xz,
+		Yx: m.Xx*m2.Yx + m.Yx*m2.Yy + m.Zx*m2.Yz,
+		Yy: m.Xy*m2.Yx + m.Yy*m2.Yy + m.Zy*m2.Yz,
+		Yz: m.Xz*m2.Yx + m.Yz*m2.Yy + m.Zz*m2.Yz,
+		Zx: m.Xx*m2.Zx + m.Yx*m2.Zy + m.Zx*m2.Zz,
+		Zy: m.Xy*m2.Zx + m.Yy*m2.Zy + m.Zy*m2.Zz,
+		Zz: m.Xz*m2.Zx + m.Yz*m2.Zy + m.Zz*m2.Zz,
+		Tx: m.Xx*m2.Tx + m.Yx*m2.Ty + m.Zx*m2.Tz + m.Tx,
+		Ty: m.Xy*m2.Tx + m.Yy*m2.Ty + m.Zy*m2.Tz + m.Ty,
+		Tz: m.Xz*m2.Tx + m.Yz*m2.Ty + m.Zz*m2.Tz + m.Tz,
+	}
+}
+
+// Invert returns the full inverse of m as a new matrix, handling
+// non-uniform scale and shear rather than assuming a pure TRS
+// composition. Returns the identity if m is singular.
+func (m *M4) Invert() *M4 {
+	det := m.Xx*(m.Yy*m.Zz-m.Zy*m.Yz) -
+		m.Xy*(m.Yx*m.Zz-m.Zx*m.Yz) +
+		m.Xz*(m.Yx*m.Zy-m.Zx*m.Yy)
+	if det == 0 {
+		return M4I()
+	}
+	id := 1 / det
+	r := &M4{
+		Xx: (m.Yy*m.Zz - m.Zy*m.Yz) * id,
+		Xy: (m.Xz*m.Zy - m.Xy*m.Zz) * id,
+		Xz: (m.Xy*m.Yz - m.Xz*m.Yy) * id,
+		Yx: (m.Yz*m.Zx - m.Yx*m.Zz) * id,
+		Yy: (m.Xx*m.Zz - m.Xz*m.Zx) * id,
+		Yz: (m.Xz*m.Yx - m.Xx*m.Yz) * id,
+		Zx: (m.Yx*m.Zy - m.Yy*m.Zx) * id,
+		Zy: (m.Xy*m.Zx - m.Xx*m.Zy) * id,
+		Zz: (m.Xx*m.Yy - m.Xy*m.Yx) * id,
+	}
+	r.Tx = -(r.Xx*m.Tx + r.Yx*m.Ty + r.Zx*m.Tz)
+	r.Ty = -(r.Xy*m.Tx + r.Yy*m.Ty + r.Zy*m.Tz)
+	r.Tz = -(r.Xz*m.Tx + r.Yz*m.Ty + r.Zz*m.Tz)
+	return r
+}
+
+// TRS decomposes m back into a translation, rotation and scale. Scale
+// is recovered from basis vector lengths; shear introduced by a
+// reparent under a non-uniform-scaled ancestor is folded into the
+// rotation basis rather than reported separately.
+func (m *M4) TRS() (loc *V3, rot *Q, scale *V3) {
+	loc = &V3{m.Tx, m.Ty, m.Tz}
+	xAxis := &V3{m.Xx, m.Xy, m.Xz}
+	yAxis := &V3{m.Yx, m.Yy, m.Yz}
+	zAxis := &V3{m.Zx, m.Zy, m.Zz}
+	sx, sy, sz := xAxis.Len(), yAxis.Len(), zAxis.Len()
+	scale = &V3{sx, sy, sz}
+	rm := M4I()
+	if sx > 0 {
+		rm.Xx, rm.Xy, rm.Xz = m.Xx/sx, m.Xy/sx, m.Xz/sx
+	}
+	if sy > 0 {
+		rm.Yx, rm.Yy, rm.Yz = m.Yx/sy, m.Yy/sy, m.Yz/sy
+	}
+	if sz > 0 {
+		rm.Zx, rm.Zy, rm.Zz = m.Zx/sz, m.Zy/sz, m.Zz/sz
+	}
+	rot = rm.Q()
+	return loc, rot, scale
+}
+
+// Q extracts the rotation quaternion from the (assumed orthonormal)
+// basis of m.
+func (m *M4) Q() *Q {
+	tr := m.Xx + m.Yy + m.Zz
+	switch {
+	case tr > 0:
+		s := 0.5 / math.Sqrt(tr+1)
+		return &Q{(m.Yz - m.Zy) * s, (m.Zx - m.Xz) * s, (m.Xy - m.Yx) * s, 0.25 / s}
+	case m.Xx > m.Yy && m.Xx > m.Zz:
+		s := 2 * math.Sqrt(1+m.Xx-m.Yy-m.Zz)
+		return &Q{0.25 * s, (m.Yx + m.Xy) / s, (m.Zx + m.Xz) / s, (m.Yz - m.Zy) / s}
+	case m.Yy > m.Zz:
+		s := 2 * math.Sqrt(1+m.Yy-m.Xx-m.Zz)
+		return &Q{(m.Yx + m.Xy) / s, 0.25 * s, (m.Zy + m.Yz) / s, (m.Zx - m.Xz) / s}
+	default:
+		s := 2 * math.Sqrt(1+m.Zz-m.Xx-m.Yy)
+		return &Q{(m.Zx + m.Xz) / s, (m.Zy + m.Yz) / s, 0.25 * s, (m.Xy - m.Yx) / s}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package lin
+
+import "math"
+
+// Q is a quaternion used to track part and camera orientation without
+// the gimbal lock and interpolation problems inherent in Euler angles.
+type Q struct {
+	X, Y, Z, W float64
+}
+
+// QI is the identity quaternion, ie: no rotation.
+func QI() *Q { return &Q{0, 0, 0, 1} }
+
+// Mult combines q and q2, applying q2 first, as a new quaternion.
+func (q *Q) Mult(q2 *Q) *Q {
+	return &Q{
+		X: q.W*q2.X + q.X*q2.W + q.Y*q2.Z - q.Z*q2.Y,
+		Y: q.W*q2.Y - q.X*q2.Z + q.Y*q2.W + q.Z*q2.X,
+		Z: q.W*q2.Z + q.X*q2.Y - q.Y*q2.X + q.Z*q2.W,
+		W: q.W*q2.W - q.X*q2.X - q.Y*q2.Y - q.Z*q2.Z,
+	}
+}
+
+// Inv returns the inverse (conjugate, since q is expected to be unit
+// length) of q as a new quaternion.
+func (q *Q) Inv() *Q { return &Q{-q.X, -q.Y, -q.Z, q.W} }
+
+// AA sets q to the rotation of angle degrees around the given axis
+// and returns q.
+func (q *Q) AA(ax, ay, az, angle float64) *Q {
+	rad := angle * math.Pi / 180 * 0.5
+	s := math.Sin(rad)
+	q.X, q.Y, q.Z, q.W = ax*s, ay*s, az*s, math.Cos(rad)
+	return q
+}
+
+// M4 returns the rotation matrix for q.
+func (q *Q) M4() *M4 {
+	x2, y2, z2 := q.X+q.X, q.Y+q.Y, q.Z+q.Z
+	xx, yy, zz := q.X*x2, q.Y*y2, q.Z*z2
+	xy, xz, yz := q.X*y2, q.X*z2, q.Y*z2
+	wx, wy, wz := q.W*x2, q.W*y2, q.W*z2
+	return &M4{
+		Xx: 1 - (yy + zz), Xy: xy + wz, Xz: xz - wy,
+		Yx: xy - wz, Yy: 1 - (xx + zz), Yz: yz + wx,
+		Zx: xz + wy, Zy: yz - wx, Zz: 1 - (xx + yy),
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package lin
+
+import "math"
+
+// V3 is a 3D vector or point. Its methods leave v unchanged and return
+// any vector result as a new vector.
+type V3 struct {
+	X, Y, Z float64
+}
+
+// Add returns v+v2 as a new vector.
+func (v *V3) Add(v2 *V3) *V3 { return &V3{v.X + v2.X, v.Y + v2.Y, v.Z + v2.Z} }
+
+// Sub returns v-v2 as a new vector.
+func (v *V3) Sub(v2 *V3) *V3 { return &V3{v.X - v2.X, v.Y - v2.Y, v.Z - v2.Z} }
+
+// Scale returns v scaled by s as a new vector.
+func (v *V3) Scale(s float64) *V3 { return &V3{v.X * s, v.Y * s, v.Z * s} }
+
+// Dot returns the dot product of v and v2.
+func (v *V3) Dot(v2 *V3) float64 { return v.X*v2.X + v.Y*v2.Y + v.Z*v2.Z }
+
+// Cross returns v×v2 as a new vector.
+func (v *V3) Cross(v2 *V3) *V3 {
+	return &V3{v.Y*v2.Z - v.Z*v2.Y, v.Z*v2.X - v.X*v2.Z, v.X*v2.Y - v.Y*v2.X}
+}
+
+// Len returns the length (magnitude) of v.
+func (v *V3) Len() float64 { return math.Sqrt(v.Dot(v)) }
+
+// Unit returns v scaled to unit length as a new vector. The zero
+// vector is returned unchanged.
+func (v *V3) Unit() *V3 {
+	if l := v.Len(); l > 0 {
+		return v.Scale(1 / l)
+	}
+	return &V3{0, 0, 0}
+}
@@ -0,0 +1,162 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// Mesh is a triangle mesh: a list of vertex positions, their per-
+// vertex normals, and triangles referencing those vertices by index.
+type Mesh struct {
+	name  string
+	verts []*lin.V3
+	norms []*lin.V3
+	tris  [][3]int
+}
+
+// NewMesh creates a mesh from raw vertex positions and triangle vertex
+// indices. Normals are computed immediately.
+func NewMesh(name string, verts []*lin.V3, tris [][3]int) *Mesh {
+	m := &Mesh{name: name, verts: verts, tris: tris}
+	m.computeNormals()
+	return m
+}
+
+// computeNormals sets each vertex's normal to the area-weighted
+// average of the face normals of its adjacent triangles.
+func (m *Mesh) computeNormals() {
+	m.norms = make([]*lin.V3, len(m.verts))
+	for i := range m.norms {
+		m.norms[i] = &lin.V3{}
+	}
+	for _, t := range m.tris {
+		v0, v1, v2 := m.verts[t[0]], m.verts[t[1]], m.verts[t[2]]
+		faceNorm := v1.Sub(v0).Cross(v2.Sub(v0))
+		for _, vi := range t {
+			m.norms[vi] = m.norms[vi].Add(faceNorm)
+		}
+	}
+	for i, n := range m.norms {
+		m.norms[i] = n.Unit()
+	}
+}
+
+// edgeKey identifies an undirected edge by its two (ordered) vertex
+// indices.
+type edgeKey struct{ a, b int }
+
+func newEdgeKey(a, b int) edgeKey {
+	if a > b {
+		a, b = b, a
+	}
+	return edgeKey{a, b}
+}
+
+// edgeInfo tracks how many triangles touch an edge (1 means a
+// boundary edge) and the vertex opposite the edge in each of those
+// triangles, needed for the interior edge-midpoint rule.
+type edgeInfo struct {
+	count int
+	opp   [2]int
+}
+
+// loopSubdivide runs one step of Loop subdivision over src, returning
+// a new, smoother mesh with 4x the triangles. Interior edges get a
+// midpoint weighted 3/8,3/8,1/8,1/8 across the edge and its two
+// opposite vertices; boundary edges use a plain 1/2,1/2 midpoint.
+// Original vertices are repositioned by their valence, boundary
+// vertices using the two boundary edges that touch them.
+func loopSubdivide(src *Mesh) *Mesh {
+	nv := len(src.verts)
+	edges := map[edgeKey]*edgeInfo{}
+	neighbors := make([]map[int]bool, nv)
+	for i := range neighbors {
+		neighbors[i] = map[int]bool{}
+	}
+	addEdge := func(a, b, opp int) {
+		k := newEdgeKey(a, b)
+		ei, ok := edges[k]
+		if !ok {
+			ei = &edgeInfo{}
+			edges[k] = ei
+		}
+		if ei.count < 2 {
+			ei.opp[ei.count] = opp
+		}
+		ei.count++
+		neighbors[a][b] = true
+		neighbors[b][a] = true
+	}
+	for _, t := range src.tris {
+		addEdge(t[0], t[1], t[2])
+		addEdge(t[1], t[2], t[0])
+		addEdge(t[2], t[0], t[1])
+	}
+
+	// new vertices: the (repositioned) originals followed by one per edge.
+	verts := make([]*lin.V3, nv, nv+len(edges))
+	midIndex := make(map[edgeKey]int, len(edges))
+	for k, ei := range edges {
+		v0, v1 := src.verts[k.a], src.verts[k.b]
+		var pos *lin.V3
+		if ei.count >= 2 {
+			vl, vr := src.verts[ei.opp[0]], src.verts[ei.opp[1]]
+			pos = v0.Scale(3.0 / 8).Add(v1.Scale(3.0 / 8)).Add(vl.Scale(1.0 / 8)).Add(vr.Scale(1.0 / 8))
+		} else {
+			pos = v0.Add(v1).Scale(0.5)
+		}
+		verts = append(verts, pos)
+		midIndex[k] = len(verts) - 1
+	}
+
+	for v := 0; v < nv; v++ {
+		var boundary []int
+		for n := range neighbors[v] {
+			if edges[newEdgeKey(v, n)].count == 1 {
+				boundary = append(boundary, n)
+			}
+		}
+		if len(boundary) > 0 {
+			vp := src.verts[boundary[0]]
+			vn := vp
+			if len(boundary) > 1 {
+				vn = src.verts[boundary[1]]
+			}
+			verts[v] = vp.Add(vn).Scale(0.125).Add(src.verts[v].Scale(0.75))
+			continue
+		}
+		n := len(neighbors[v])
+		sum := &lin.V3{}
+		for nb := range neighbors[v] {
+			sum = sum.Add(src.verts[nb])
+		}
+		beta := loopBeta(n)
+		verts[v] = src.verts[v].Scale(1 - float64(n)*beta).Add(sum.Scale(beta))
+	}
+
+	tris := make([][3]int, 0, len(src.tris)*4)
+	for _, t := range src.tris {
+		m01 := midIndex[newEdgeKey(t[0], t[1])]
+		m12 := midIndex[newEdgeKey(t[1], t[2])]
+		m20 := midIndex[newEdgeKey(t[2], t[0])]
+		tris = append(tris,
+			[3]int{t[0], m01, m20},
+			[3]int{t[1], m12, m01},
+			[3]int{t[2], m20, m12},
+			[3]int{m01, m12, m20},
+		)
+	}
+	return NewMesh(src.name, verts, tris)
+}
+
+// loopBeta is the interior-vertex weight for Loop subdivision at the
+// given valence n.
+func loopBeta(n int) float64 {
+	fn := float64(n)
+	t := 3.0/8.0 + 0.25*math.Cos(2*math.Pi/fn)
+	return (1 / fn) * (5.0/8.0 - t*t)
+}
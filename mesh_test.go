@@ -0,0 +1,42 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+func TestLoopSubdivideQuadruplesTriangles(t *testing.T) {
+	tri := NewMesh("tri", []*lin.V3{
+		{X: 0, Y: 0, Z: 0},
+		{X: 1, Y: 0, Z: 0},
+		{X: 0, Y: 1, Z: 0},
+	}, [][3]int{{0, 1, 2}})
+
+	sub := loopSubdivide(tri)
+	if len(sub.tris) != 4*len(tri.tris) {
+		t.Fatalf("got %d triangles, want %d", len(sub.tris), 4*len(tri.tris))
+	}
+	if len(sub.verts) != len(tri.verts)+3 { // one new vertex per edge.
+		t.Fatalf("got %d verts, want %d", len(sub.verts), len(tri.verts)+3)
+	}
+	if len(sub.norms) != len(sub.verts) {
+		t.Fatalf("got %d norms, want one per vertex (%d)", len(sub.norms), len(sub.verts))
+	}
+	for i, n := range sub.norms {
+		if l := n.Len(); math.Abs(l-1) > 1e-9 {
+			t.Fatalf("norm %d has length %v, want a unit vector", i, l)
+		}
+	}
+}
+
+func TestLoopBetaMatchesClassicValenceSixWeight(t *testing.T) {
+	// The standard Loop beta at the common interior valence of 6 is 1/16.
+	if got, want := loopBeta(6), 1.0/16.0; math.Abs(got-want) > 1e-9 {
+		t.Fatalf("loopBeta(6) = %v, want %v", got, want)
+	}
+}
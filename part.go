@@ -0,0 +1,243 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import "github.com/gazed/vu/math/lin"
+
+// Part is a node in a scenes graph. Parts can be transformed (moved,
+// rotated, scaled) and rendered using a Role. Parts are arranged in a
+// tree: moving a parent moves all of its children.
+type Part interface {
+	AddPart() Part       // Create and add a new (initially identity) child part.
+	RemPart(p Part)      // Remove and discard the given child part.
+	SetLocation(x, y, z float64) Part
+	SetRotation(x, y, z, w float64) Part
+	SetScale(x, y, z float64) Part
+	Spin(x, y, z float64) // Rotate by the given degrees around each axis.
+	SetRole(name string) Role
+	Role() Role
+
+	// SetParentKeepTransform moves this part from its current parent to
+	// newParent, adjusting the local transform so the part's world
+	// transform is unchanged. A nil newParent reparents to the part's
+	// scene root.
+	SetParentKeepTransform(newParent Part)
+	// ClearParentKeepTransform detaches this part into its scene's root,
+	// preserving its current world transform.
+	ClearParentKeepTransform()
+}
+
+// part is the default Part implementation.
+type part struct {
+	parent   *part
+	children []*part
+
+	loc   *lin.V3
+	rot   *lin.Q
+	scale *lin.V3
+	role  *role
+
+	local      *lin.M4 // cached local transform, valid when !localDirty.
+	world      *lin.M4 // cached world transform, valid when !dirty.
+	localDirty bool    // local must be rebuilt from loc/rot/scale.
+	dirty      bool    // world (and every descendant's world) must be recomputed.
+
+	owner *scene // scene this part's tree belongs to, for BVH dirty tracking.
+}
+
+// newPart creates an identity part with the given parent. A nil
+// parent means the part is a root of its scene; owner is set
+// separately by scene.AddPart in that case.
+func newPart(parent *part) *part {
+	p := &part{parent: parent}
+	p.loc = &lin.V3{}
+	p.rot = lin.QI()
+	p.scale = &lin.V3{1, 1, 1}
+	p.localDirty = true
+	p.dirty = true
+	if parent != nil {
+		p.owner = parent.owner
+	}
+	return p
+}
+
+func (p *part) AddPart() Part {
+	child := newPart(p)
+	p.children = append(p.children, child)
+	return child
+}
+
+func (p *part) RemPart(rem Part) {
+	child, ok := rem.(*part)
+	if !ok {
+		return
+	}
+	for i, c := range p.children {
+		if c == child {
+			p.children = append(p.children[:i], p.children[i+1:]...)
+			child.parent = nil
+			return
+		}
+	}
+}
+
+func (p *part) SetLocation(x, y, z float64) Part {
+	p.loc = &lin.V3{x, y, z}
+	p.markDirty()
+	return p
+}
+
+func (p *part) SetRotation(x, y, z, w float64) Part {
+	p.rot = &lin.Q{x, y, z, w}
+	p.markDirty()
+	return p
+}
+
+func (p *part) SetScale(x, y, z float64) Part {
+	p.scale = &lin.V3{x, y, z}
+	p.markDirty()
+	return p
+}
+
+func (p *part) Spin(x, y, z float64) {
+	spin := lin.QI().AA(1, 0, 0, x).Mult(lin.QI().AA(0, 1, 0, y)).Mult(lin.QI().AA(0, 0, 1, z))
+	p.rot = p.rot.Mult(spin)
+	p.markDirty()
+}
+
+func (p *part) SetRole(name string) Role {
+	p.role = newRole(name)
+	return p.role
+}
+
+func (p *part) Role() Role { return p.role }
+
+// SetParentKeepTransform splices p out of its current parent's
+// children and into newParent's, recomputing p's local transform so
+// that newParent's-world * p's-new-local still equals p's old world
+// transform. A nil newParent (or one that isn't a *part) moves p to
+// its scene's root, treated as an identity parent.
+func (p *part) SetParentKeepTransform(newParent Part) {
+	np, _ := newParent.(*part)
+	if np != nil && (np == p || p.isAncestorOf(np)) {
+		return // np is p itself or one of p's descendants.
+	}
+	world := p.worldTransform()
+	owner := p.owner
+	if p.parent != nil {
+		p.parent.removeChild(p)
+	} else if owner != nil {
+		owner.RemPart(p) // p was one of its scene's root parts.
+	}
+	p.parent = np
+	if np != nil {
+		np.children = append(np.children, p)
+		p.setOwner(np.owner)
+		p.setLocalMatrix(np.worldTransform().Invert().Mult(world))
+	} else {
+		if owner != nil {
+			owner.parts = append(owner.parts, p)
+			owner.structDirty = true
+		}
+		p.setOwner(owner)
+		p.setLocalMatrix(world)
+	}
+}
+
+// setOwner assigns owner to p and every part beneath it, so a
+// reparent across scenes keeps BVH dirty tracking pointed at the
+// right scene.
+func (p *part) setOwner(owner *scene) {
+	p.owner = owner
+	for _, c := range p.children {
+		c.setOwner(owner)
+	}
+}
+
+// ClearParentKeepTransform detaches p to its scene's root, preserving
+// its current world transform.
+func (p *part) ClearParentKeepTransform() { p.SetParentKeepTransform(nil) }
+
+// isAncestorOf reports whether p is an ancestor of n, ie: whether n
+// lies in the subtree rooted at p.
+func (p *part) isAncestorOf(n *part) bool {
+	for cur := n; cur != nil; cur = cur.parent {
+		if cur == p {
+			return true
+		}
+	}
+	return false
+}
+
+// removeChild splices child out of p's children, if present.
+func (p *part) removeChild(child *part) {
+	for i, c := range p.children {
+		if c == child {
+			p.children = append(p.children[:i], p.children[i+1:]...)
+			return
+		}
+	}
+}
+
+// markDirty invalidates p's cached local transform - p.loc/rot/scale
+// changed, so it must be rebuilt from them - and every part's (p's and
+// its descendants') cached world transform, since a world transform
+// can only be derived once its ancestors' transforms are known. It
+// also flags p's scene (if any) so the next cull refits the BVH
+// instead of trusting a tree built against now-stale world AABBs.
+func (p *part) markDirty() {
+	p.localDirty = true
+	p.markWorldDirty()
+}
+
+// markWorldDirty invalidates the cached world transform for p and
+// every part beneath it, without touching p's own cached local
+// transform. setLocalMatrix uses this alone: it assigns p.local
+// directly, and a localDirty rebuild from p.loc/rot/scale would
+// silently replace that exact matrix with a lossy TRS approximation.
+func (p *part) markWorldDirty() {
+	p.dirty = true
+	if p.owner != nil {
+		p.owner.transformDirty = true
+	}
+	for _, c := range p.children {
+		c.markWorldDirty()
+	}
+}
+
+// localTransform returns (building and caching if necessary) the
+// part's local transform matrix.
+func (p *part) localTransform() *lin.M4 {
+	if p.local == nil || p.localDirty {
+		p.local = lin.NewTRS(p.loc, p.rot, p.scale)
+		p.localDirty = false
+	}
+	return p.local
+}
+
+// worldTransform returns (building and caching if necessary) the
+// part's world transform matrix, ie: its local transform composed
+// with its ancestors' transforms.
+func (p *part) worldTransform() *lin.M4 {
+	if p.world == nil || p.dirty {
+		if p.parent == nil {
+			p.world = p.localTransform()
+		} else {
+			p.world = p.parent.worldTransform().Mult(p.localTransform())
+		}
+		p.dirty = false
+	}
+	return p.world
+}
+
+// setLocalMatrix assigns a general (possibly sheared) local transform
+// directly, bypassing the loc/rot/scale TRS composition. This is used
+// when a reparent needs to preserve a world transform that a simple
+// TRS decomposition would otherwise distort.
+func (p *part) setLocalMatrix(m *lin.M4) {
+	p.loc, p.rot, p.scale = m.TRS()
+	p.local = m
+	p.localDirty = false
+	p.markWorldDirty()
+}
@@ -0,0 +1,62 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+// Role binds a mesh, material and shader together to describe how a
+// Part is rendered. The "flat" and "gouraud" shaders render the mesh
+// as given; "subsurf" additionally Loop-subdivides it at load time for
+// a rounded silhouette, controlled by SetSubdivisions.
+type Role interface {
+	SetMesh(name string) Role
+	SetMaterial(name string) Role
+	SetLightLocation(x, y, z float64) Role
+	SetLightColour(r, g, b float64) Role
+
+	// SetSubdivisions sets the number of Loop subdivision steps applied
+	// to this role's mesh. Only meaningful for the "subsurf" shader;
+	// ignored (stays 0) for other shaders.
+	SetSubdivisions(n int) Role
+}
+
+// role is the default Role implementation.
+type role struct {
+	shader       string
+	mesh         string
+	material     string
+	lightLoc     [3]float64
+	lightCol     [3]float64
+	subdivisions int
+}
+
+// newRole creates a role using the named shader.
+func newRole(shader string) *role { return &role{shader: shader} }
+
+func (r *role) SetMesh(name string) Role     { r.mesh = name; return r }
+func (r *role) SetMaterial(name string) Role { r.material = name; return r }
+func (r *role) SetLightLocation(x, y, z float64) Role {
+	r.lightLoc = [3]float64{x, y, z}
+	return r
+}
+func (r *role) SetLightColour(red, grn, blu float64) Role {
+	r.lightCol = [3]float64{red, grn, blu}
+	return r
+}
+
+func (r *role) SetSubdivisions(n int) Role {
+	if r.shader == "subsurf" {
+		r.subdivisions = n
+	}
+	return r
+}
+
+// resolvedMesh returns the mesh this role should draw: the base mesh
+// for "flat"/"gouraud", or the cached Loop-subdivided variant for
+// "subsurf".
+func (r *role) resolvedMesh() (*Mesh, bool) {
+	n := 0
+	if r.shader == "subsurf" {
+		n = r.subdivisions
+	}
+	return assets.resolveMesh(r.mesh, n)
+}
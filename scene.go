@@ -0,0 +1,214 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import "io"
+
+// Scene is a top level grouping of parts sharing a single camera. An
+// engine has one or more scenes, eg: a 3D world scene and a 2D HUD
+// scene.
+type Scene interface {
+	AddPart() Part  // Create and add a new root level part.
+	RemPart(p Part) // Remove and discard the given root level part.
+	Cam() Camera
+	Set2D() // Treat the scene as an orthographic overlay.
+
+	// EnableBVH turns the scene's BVH culling on or off. It is off by
+	// default since small scenes don't benefit from it.
+	EnableBVH(on bool)
+	// BVHStats reports node/leaf counts and how many times the BVH has
+	// been refit in place versus rebuilt from scratch.
+	BVHStats() (nodes, leaves, refits, rebuilds int)
+
+	// Save writes the scene's full part hierarchy - transforms, roles
+	// and child structure - to w using the default binary SceneCodec, so
+	// it can later be restored with Engine.LoadScene.
+	Save(w io.Writer) error
+}
+
+// scene is the default Scene implementation.
+type scene struct {
+	parts []*part
+	cam   *camera
+	is2D  bool
+
+	bvhOn bool
+	bvh   *bvh
+
+	// structDirty/transformDirty mark that the BVH needs resyncing
+	// before the next cull: structDirty when a part was added/removed,
+	// transformDirty when some part's transform changed. Tracking these
+	// separately from bvh.sync's own rebuild-vs-refit choice is what
+	// lets cull skip the BVH update entirely on an unchanged frame.
+	structDirty    bool
+	transformDirty bool
+}
+
+// newScene creates an empty scene with a default camera.
+func newScene() *scene { return &scene{cam: newCamera()} }
+
+func (s *scene) AddPart() Part {
+	p := newPart(nil)
+	p.owner = s
+	s.parts = append(s.parts, p)
+	s.structDirty = true
+	return p
+}
+
+func (s *scene) RemPart(rem Part) {
+	child, ok := rem.(*part)
+	if !ok {
+		return
+	}
+	for i, p := range s.parts {
+		if p == child {
+			s.parts = append(s.parts[:i], s.parts[i+1:]...)
+			s.structDirty = true
+			return
+		}
+	}
+}
+
+func (s *scene) Cam() Camera { return s.cam }
+func (s *scene) Set2D()      { s.is2D = true }
+
+func (s *scene) EnableBVH(on bool) {
+	s.bvhOn = on
+	if on && s.bvh == nil {
+		s.bvh = newBVH()
+		s.rebuildBVH()
+	}
+}
+
+func (s *scene) BVHStats() (nodes, leaves, refits, rebuilds int) {
+	if s.bvh == nil {
+		return 0, 0, 0, 0
+	}
+	return s.bvh.nodeCount(), s.bvh.leafCount(), s.bvh.refits, s.bvh.rebuilds
+}
+
+// cull consults the BVH to return only the parts visible in the
+// camera's current frustum, resyncing it first if a part was
+// added/removed or had its transform changed since the last call.
+// Called once a frame before draw submission.
+func (s *scene) cull() []Part {
+	if !s.bvhOn || s.bvh == nil {
+		return s.allParts()
+	}
+	if s.bvh.nodes == nil || s.structDirty || s.transformDirty {
+		s.bvh.sync(s.collectLeaves())
+		s.structDirty, s.transformDirty = false, false
+	}
+	visible := s.bvh.query(s.cam.frustum())
+	out := make([]Part, len(visible))
+	for i, p := range visible {
+		out[i] = p
+	}
+	return out
+}
+
+func (s *scene) allParts() []Part {
+	var out []Part
+	var walk func(p *part)
+	walk = func(p *part) {
+		out = append(out, p)
+		for _, c := range p.children {
+			walk(c)
+		}
+	}
+	for _, root := range s.parts {
+		walk(root)
+	}
+	return out
+}
+
+// collectLeaves flattens the scene's part trees into a list of parts,
+// the primitives the BVH is built and queried over. Their AABBs are
+// not included; the BVH derives them with part.worldAABB.
+func (s *scene) collectLeaves() []*part {
+	var out []*part
+	var walk func(p *part)
+	walk = func(p *part) {
+		out = append(out, p)
+		for _, c := range p.children {
+			walk(c)
+		}
+	}
+	for _, root := range s.parts {
+		walk(root)
+	}
+	return out
+}
+
+func (s *scene) rebuildBVH() {
+	s.bvh.sync(s.collectLeaves())
+	s.structDirty, s.transformDirty = false, false
+}
+
+func (s *scene) Save(w io.Writer) error {
+	return BinaryCodec.Encode(w, s.flatten())
+}
+
+// flatten walks the scene depth first, assigning each part the index
+// it's recorded at. A part's parent is always visited (and so
+// assigned an index) before the part itself, which is what lets
+// loadScene rebuild the tree in one forward pass and makes a cycle in
+// the saved data structurally impossible.
+func (s *scene) flatten() []SceneNode {
+	var nodes []SceneNode
+	var walk func(p *part, parentIdx int)
+	walk = func(p *part, parentIdx int) {
+		idx := len(nodes)
+		nodes = append(nodes, sceneNodeOf(p, parentIdx))
+		for _, c := range p.children {
+			walk(c, idx)
+		}
+	}
+	for _, root := range s.parts {
+		walk(root, -1)
+	}
+	return nodes
+}
+
+// sceneNodeOf captures p's own transform and role as a SceneNode; its
+// child links are reconstructed separately via parentIdx.
+func sceneNodeOf(p *part, parentIdx int) SceneNode {
+	n := SceneNode{
+		Parent: parentIdx,
+		Loc:    [3]float64{p.loc.X, p.loc.Y, p.loc.Z},
+		Rot:    [4]float64{p.rot.X, p.rot.Y, p.rot.Z, p.rot.W},
+		Scale:  [3]float64{p.scale.X, p.scale.Y, p.scale.Z},
+	}
+	if p.role != nil {
+		n.Shader = p.role.shader
+		n.Mesh = p.role.mesh
+		n.Material = p.role.material
+		n.LightLoc = p.role.lightLoc
+		n.LightCol = p.role.lightCol
+		n.Subdivisions = p.role.subdivisions
+	}
+	return n
+}
+
+// worldAABB returns p's axis aligned world-space bounding box, derived
+// from the world transform applied to a unit (-1..1 per axis) local
+// bounding cube.
+func (p *part) worldAABB() aabb {
+	w := p.worldTransform()
+	cx, cy, cz := w.Tx, w.Ty, w.Tz
+	ex := absf(w.Xx) + absf(w.Yx) + absf(w.Zx)
+	ey := absf(w.Xy) + absf(w.Yy) + absf(w.Zy)
+	ez := absf(w.Xz) + absf(w.Yz) + absf(w.Zz)
+	return aabb{
+		min: [3]float64{cx - ex, cy - ey, cz - ez},
+		max: [3]float64{cx + ex, cy + ey, cz + ez},
+	}
+}
+
+func absf(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
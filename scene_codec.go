@@ -0,0 +1,228 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+// sceneMagic identifies a vu binary scene file; sceneVersion lets
+// loadScene reject files saved by an incompatible future format.
+const (
+	sceneMagic   = "VUSC"
+	sceneVersion = uint32(1)
+)
+
+// SceneNode is one flattened Part: its parent (by index into the same
+// slice, -1 for a scene root), its TRS transform, and its role.
+// Scene.flatten always orders nodes so a part's parent has a lower
+// index than the part itself.
+type SceneNode struct {
+	Parent       int
+	Loc          [3]float64
+	Rot          [4]float64
+	Scale        [3]float64
+	Shader       string
+	Mesh         string
+	Material     string
+	LightLoc     [3]float64
+	LightCol     [3]float64
+	Subdivisions int
+}
+
+// SceneCodec converts a flattened scene to and from a byte stream.
+// The binary BinaryCodec is the default; JSONCodec is provided for
+// human-readable saves, and callers can supply their own, eg: to write
+// glTF.
+type SceneCodec interface {
+	Encode(w io.Writer, nodes []SceneNode) error
+	Decode(r io.Reader) ([]SceneNode, error)
+}
+
+// BinaryCodec is the compact, versioned default SceneCodec.
+var BinaryCodec SceneCodec = binaryCodec{}
+
+// JSONCodec is a SceneCodec that reads/writes the scene as JSON.
+var JSONCodec SceneCodec = jsonCodec{}
+
+// loadScene decodes nodes with codec, rebuilds the part tree they
+// describe, and reports any mesh or material that fails to resolve in
+// the engine's asset cache (see RegisterMesh, RegisterMaterial) rather
+// than silently returning a scene that draws nothing.
+func loadScene(r io.Reader, codec SceneCodec) (*scene, error) {
+	nodes, err := codec.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+	s := newScene()
+	parts := make([]*part, len(nodes))
+	var missingMeshes, missingMaterials []string
+	for i, n := range nodes {
+		var p *part
+		if n.Parent < 0 {
+			p = newPart(nil)
+			p.owner = s
+			s.parts = append(s.parts, p)
+			s.structDirty = true
+		} else if n.Parent < i {
+			parent := parts[n.Parent]
+			p = newPart(parent)
+			parent.children = append(parent.children, p)
+		} else {
+			return nil, fmt.Errorf("vu: scene node %d has invalid parent index %d", i, n.Parent)
+		}
+		p.loc = &lin.V3{X: n.Loc[0], Y: n.Loc[1], Z: n.Loc[2]}
+		p.rot = &lin.Q{X: n.Rot[0], Y: n.Rot[1], Z: n.Rot[2], W: n.Rot[3]}
+		p.scale = &lin.V3{X: n.Scale[0], Y: n.Scale[1], Z: n.Scale[2]}
+		p.localDirty = true
+		p.dirty = true
+		if n.Shader != "" {
+			rl := newRole(n.Shader)
+			rl.mesh, rl.material = n.Mesh, n.Material
+			rl.lightLoc, rl.lightCol = n.LightLoc, n.LightCol
+			rl.subdivisions = n.Subdivisions
+			p.role = rl
+			if n.Mesh != "" {
+				if _, ok := assets.resolveMesh(n.Mesh, 0); !ok {
+					missingMeshes = append(missingMeshes, n.Mesh)
+				}
+			}
+			if n.Material != "" && !assets.hasMaterial(n.Material) {
+				missingMaterials = append(missingMaterials, n.Material)
+			}
+		}
+		parts[i] = p
+	}
+	if len(missingMeshes) > 0 || len(missingMaterials) > 0 {
+		return nil, fmt.Errorf("vu: scene references unresolved assets: meshes=%v materials=%v", missingMeshes, missingMaterials)
+	}
+	return s, nil
+}
+
+// binaryCodec is the length-prefixed binary SceneCodec.
+type binaryCodec struct{}
+
+func (binaryCodec) Encode(w io.Writer, nodes []SceneNode) error {
+	if _, err := io.WriteString(w, sceneMagic); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, sceneVersion); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint32(len(nodes))); err != nil {
+		return err
+	}
+	writeStr := func(s string) error {
+		if err := binary.Write(w, binary.LittleEndian, uint32(len(s))); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, s)
+		return err
+	}
+	for _, n := range nodes {
+		if err := binary.Write(w, binary.LittleEndian, int32(n.Parent)); err != nil {
+			return err
+		}
+		for _, v := range [][3]float64{n.Loc, n.Scale, n.LightLoc, n.LightCol} {
+			if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+				return err
+			}
+		}
+		if err := binary.Write(w, binary.LittleEndian, n.Rot); err != nil {
+			return err
+		}
+		if err := binary.Write(w, binary.LittleEndian, int32(n.Subdivisions)); err != nil {
+			return err
+		}
+		for _, s := range []string{n.Shader, n.Mesh, n.Material} {
+			if err := writeStr(s); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (binaryCodec) Decode(r io.Reader) ([]SceneNode, error) {
+	magic := make([]byte, len(sceneMagic))
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != sceneMagic {
+		return nil, fmt.Errorf("vu: not a scene file (bad magic %q)", magic)
+	}
+	var version, count uint32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, err
+	}
+	if version != sceneVersion {
+		return nil, fmt.Errorf("vu: unsupported scene file version %d", version)
+	}
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	readStr := func() (string, error) {
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return "", err
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+	nodes := make([]SceneNode, count)
+	for i := range nodes {
+		n := &nodes[i]
+		var parent int32
+		if err := binary.Read(r, binary.LittleEndian, &parent); err != nil {
+			return nil, err
+		}
+		n.Parent = int(parent)
+		for _, v := range []*[3]float64{&n.Loc, &n.Scale, &n.LightLoc, &n.LightCol} {
+			if err := binary.Read(r, binary.LittleEndian, v); err != nil {
+				return nil, err
+			}
+		}
+		if err := binary.Read(r, binary.LittleEndian, &n.Rot); err != nil {
+			return nil, err
+		}
+		var subdiv int32
+		if err := binary.Read(r, binary.LittleEndian, &subdiv); err != nil {
+			return nil, err
+		}
+		n.Subdivisions = int(subdiv)
+		var err error
+		if n.Shader, err = readStr(); err != nil {
+			return nil, err
+		}
+		if n.Mesh, err = readStr(); err != nil {
+			return nil, err
+		}
+		if n.Material, err = readStr(); err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+// jsonCodec is a human readable SceneCodec, handy for diffing saved
+// scenes or authoring them by hand.
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, nodes []SceneNode) error {
+	return json.NewEncoder(w).Encode(nodes)
+}
+
+func (jsonCodec) Decode(r io.Reader) ([]SceneNode, error) {
+	var nodes []SceneNode
+	err := json.NewDecoder(r).Decode(&nodes)
+	return nodes, err
+}
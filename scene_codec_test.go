@@ -0,0 +1,66 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package vu
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gazed/vu/math/lin"
+)
+
+func TestSceneSaveLoadRoundTrip(t *testing.T) {
+	RegisterMesh(NewMesh("rt-cube", []*lin.V3{{X: 0, Y: 0, Z: 0}, {X: 1, Y: 0, Z: 0}, {X: 0, Y: 1, Z: 0}}, [][3]int{{0, 1, 2}}))
+	RegisterMaterial("rt-green")
+
+	s := newScene()
+	root := s.AddPart()
+	root.SetLocation(1, 2, 3)
+	root.SetRole("flat").SetMesh("rt-cube").SetMaterial("rt-green")
+	child := root.AddPart()
+	child.SetLocation(0, 1, 0)
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := loadScene(&buf, BinaryCodec)
+	if err != nil {
+		t.Fatalf("loadScene: %v", err)
+	}
+	if len(loaded.parts) != 1 {
+		t.Fatalf("got %d root parts, want 1", len(loaded.parts))
+	}
+	lroot := loaded.parts[0]
+	if lroot.loc.X != 1 || lroot.loc.Y != 2 || lroot.loc.Z != 3 {
+		t.Fatalf("root location = %+v, want (1,2,3)", lroot.loc)
+	}
+	if lroot.role == nil || lroot.role.mesh != "rt-cube" || lroot.role.material != "rt-green" {
+		t.Fatalf("root role = %+v, want mesh=rt-cube material=rt-green", lroot.role)
+	}
+	if len(lroot.children) != 1 || lroot.children[0].loc.Y != 1 {
+		t.Fatalf("child not restored under its parent: %+v", lroot.children)
+	}
+}
+
+func TestLoadSceneReportsUnresolvedMeshAndMaterial(t *testing.T) {
+	s := newScene()
+	p := s.AddPart()
+	p.SetRole("flat").SetMesh("does-not-exist-mesh").SetMaterial("does-not-exist-material")
+
+	var buf bytes.Buffer
+	if err := s.Save(&buf); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	_, err := loadScene(&buf, BinaryCodec)
+	if err == nil {
+		t.Fatalf("expected an error for unresolved mesh/material, got nil")
+	}
+	if !strings.Contains(err.Error(), "does-not-exist-mesh") || !strings.Contains(err.Error(), "does-not-exist-material") {
+		t.Fatalf("error %q doesn't name both unresolved asset names", err)
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+// Package voxel provides a sparse octree for storing and rendering
+// voxel content. It generalizes the cube/block merge-and-split
+// behavior originally prototyped in the eg/sg example so that voxel
+// volumes can be built to arbitrary depth without an O(n^3) cell
+// enumeration.
+package voxel
+
+import "github.com/gazed/vu"
+
+// Material identifies the visible substance of a voxel leaf. Zero is
+// reserved for "empty".
+type Material uint8
+
+// Empty is the material of an unset voxel.
+const Empty Material = 0
+
+// cmax is the fixed number of children an octree node can have.
+const cmax = 8
+
+// Octree is a sparse voxel octree. The root covers a cube of the
+// given size centered at the origin, subdivided to a fixed depth, so
+// Set's x,y,z are always unambiguous cell coordinates in [0, 2^depth).
+// Subdivide/Set grow the tree as needed and Merge collapses uniform
+// regions back down.
+type Octree struct {
+	root       *node
+	size       float64 // length of one side of the root cube.
+	depth      int     // fixed number of octant levels below the root.
+	appearance map[Material]Appearance
+}
+
+// node is one cell of the octree. A leaf has no children and a single
+// material. An interior node has exactly cmax children; it collapses
+// back to a leaf whenever all of its children become identical
+// leaves. dirty marks a node whose subtree has changed since it was
+// last mounted, so MountInto can skip untouched subtrees.
+type node struct {
+	mat      Material
+	children [cmax]*node // nil while the node is a leaf, ie: until Subdivide or Set splits it.
+	part     vu.Part     // lazily created, mirrors this node while mounted.
+	dirty    bool
+}
+
+// Appearance maps a Material to the mesh and material name MountInto
+// assigns to the vu.Part it creates for a leaf of that material.
+type Appearance struct {
+	Mesh, Material string
+}
+
+// New creates an empty octree covering a cube of the given size,
+// addressable by cell coordinates to the given depth (a size-depth
+// tree has 2^depth cells per axis, 8^depth cells in total).
+func New(size float64, depth int) *Octree {
+	return &Octree{root: &node{mat: Empty, dirty: true}, size: size, depth: depth}
+}
+
+// SetAppearance registers the mesh and material name MountInto should
+// use for leaves of the given material. A material with no registered
+// Appearance mounts using DefaultAppearance.
+func (o *Octree) SetAppearance(mat Material, a Appearance) {
+	if o.appearance == nil {
+		o.appearance = map[Material]Appearance{}
+	}
+	o.appearance[mat] = a
+}
+
+// DefaultAppearance is used by MountInto for any populated material
+// with no Appearance registered via SetAppearance.
+var DefaultAppearance = Appearance{Mesh: "cube", Material: "green"}
+
+func (o *Octree) appearanceFor(mat Material) Appearance {
+	if a, ok := o.appearance[mat]; ok {
+		return a
+	}
+	return DefaultAppearance
+}
+
+// octant returns the 3-bit child index (x<<2|y<<1|z) for the half of
+// a node that px,py,pz (each 0 or 1) fall into.
+func octant(px, py, pz uint8) uint8 { return px<<2 | py<<1 | pz }
+
+// Subdivide splits the node at path into cmax identical children
+// carrying the node's current material. path is a sequence of octant
+// indices from the root; an empty path subdivides the root.
+func (o *Octree) Subdivide(path []uint8) {
+	stack := o.walkStack(path, true)
+	stack[len(stack)-1].split()
+	markDirty(stack)
+}
+
+// Merge collapses the node at path back into a single leaf, discarding
+// any children. It is the inverse of Subdivide and is also applied
+// automatically by Set whenever an edit makes all of a node's children
+// identical.
+func (o *Octree) Merge(path []uint8) {
+	stack := o.walkStack(path, false)
+	if n := stack[len(stack)-1]; n != nil {
+		n.collapse()
+		markDirty(stack)
+	}
+}
+
+// Set assigns mat to the voxel at cell x,y,z, allocating any
+// intermediate nodes on demand and then bubbling a maybe-collapse
+// check back up to the root. x,y,z must each be in [0, 2^o.depth).
+func (o *Octree) Set(x, y, z int, mat Material) {
+	path := cellPath(x, y, z, o.depth)
+	stack := make([]*node, 0, len(path)+1)
+	n := o.root
+	stack = append(stack, n)
+	for _, idx := range path {
+		if n.children[0] == nil {
+			n.split()
+		}
+		n = n.children[idx]
+		stack = append(stack, n)
+	}
+	n.mat = mat
+	n.children = [cmax]*node{}
+
+	// bubble a maybe-collapse check up from the edited leaf to the root,
+	// marking every node on the path dirty so MountInto revisits it.
+	for i := len(stack) - 1; i >= 0; i-- {
+		stack[i].maybeCollapse()
+		stack[i].dirty = true
+	}
+}
+
+// markDirty flags every node on stack as changed since it was last
+// mounted.
+func markDirty(stack []*node) {
+	for _, n := range stack {
+		if n != nil {
+			n.dirty = true
+		}
+	}
+}
+
+// cellPath turns a cell coordinate into a sequence of octant indices,
+// most significant bit (closest to the root) first.
+func cellPath(x, y, z, depth int) []uint8 {
+	path := make([]uint8, depth)
+	for i := depth - 1; i >= 0; i-- {
+		px := uint8(x>>uint(i)) & 1
+		py := uint8(y>>uint(i)) & 1
+		pz := uint8(z>>uint(i)) & 1
+		path[depth-1-i] = octant(px, py, pz)
+	}
+	return path
+}
+
+// CellCoord is the inverse of cellPath's addressing: it recovers the
+// x,y,z cell coordinate that Set(x,y,z,...) would walk to the i'th
+// path in depth-first octant order, for i in [0, 8^depth).
+func CellCoord(i, depth int) (x, y, z int) {
+	for k := depth - 1; k >= 0; k-- {
+		digit := (i >> uint(3*k)) & 7
+		x |= ((digit >> 2) & 1) << uint(k)
+		y |= ((digit >> 1) & 1) << uint(k)
+		z |= (digit & 1) << uint(k)
+	}
+	return x, y, z
+}
+
+// walkStack follows path from the root, optionally splitting interior
+// nodes as it goes, and returns every node visited including the
+// root. The last entry is nil if the path runs into a leaf and alloc
+// is false.
+func (o *Octree) walkStack(path []uint8, alloc bool) []*node {
+	stack := make([]*node, 0, len(path)+1)
+	n := o.root
+	stack = append(stack, n)
+	for _, idx := range path {
+		if n.children[0] == nil {
+			if !alloc {
+				stack = append(stack, nil)
+				return stack
+			}
+			n.split()
+		}
+		n = n.children[idx]
+		stack = append(stack, n)
+	}
+	return stack
+}
+
+// split turns a leaf into cmax leaves carrying the parent's material.
+func (n *node) split() {
+	if n.children[0] != nil {
+		return // already split.
+	}
+	for i := 0; i < cmax; i++ {
+		n.children[i] = &node{mat: n.mat, dirty: true}
+	}
+}
+
+// collapse discards a node's children, keeping its current material.
+func (n *node) collapse() {
+	n.children = [cmax]*node{}
+}
+
+// maybeCollapse collapses n if it has children and they are all
+// identical leaf materials.
+func (n *node) maybeCollapse() {
+	if n.children[0] == nil {
+		return
+	}
+	first := n.children[0]
+	if first.children[0] != nil {
+		return
+	}
+	mat := first.mat
+	for _, c := range n.children {
+		if c.children[0] != nil || c.mat != mat {
+			return
+		}
+	}
+	n.mat = mat
+	n.collapse()
+}
+
+// MountInto lazily creates child vu.Parts for this tree's populated
+// (non-empty) octants under part, mirroring the merge-to-single-cube /
+// split-into-eight behavior of the original cube editor. Calling this
+// again after edits only touches the subtrees changed since the last
+// call, leaving untouched Parts (and their transforms) alone.
+func (o *Octree) MountInto(part vu.Part) {
+	o.root.mount(o, part, 0, 0, 0, o.size)
+}
+
+// mount recursively reconciles a node with scene parts. A leaf gets at
+// most one vu.Part, given a Role from the tree's Appearance for its
+// material; an interior node gets one vu.Part per non-empty child,
+// recursing until leaves are reached. A node whose subtree hasn't
+// changed since it was last mounted is left untouched.
+func (n *node) mount(o *Octree, into vu.Part, cx, cy, cz, size float64) {
+	if !n.dirty {
+		return
+	}
+	n.dirty = false
+	if n.children[0] == nil {
+		if n.mat == Empty {
+			if n.part != nil {
+				into.RemPart(n.part)
+				n.part = nil
+			}
+			return
+		}
+		if n.part == nil {
+			n.part = into.AddPart()
+		}
+		n.part.SetLocation(cx, cy, cz)
+		n.part.SetScale(size, size, size)
+		app := o.appearanceFor(n.mat)
+		n.part.SetRole("flat").SetMesh(app.Mesh).SetMaterial(app.Material)
+		return
+	}
+	if n.part != nil {
+		into.RemPart(n.part)
+		n.part = nil
+	}
+	half := size * 0.5
+	quart := size * 0.25
+	for i := 0; i < cmax; i++ {
+		px := float64((i>>2)&1)*2 - 1
+		py := float64((i>>1)&1)*2 - 1
+		pz := float64(i&1)*2 - 1
+		n.children[i].mount(o, into, cx+px*quart, cy+py*quart, cz+pz*quart, half)
+	}
+}
+
+// Leaf describes one populated octree leaf: its center, side length
+// and material.
+type Leaf struct {
+	X, Y, Z, Size float64
+	Mat           Material
+}
+
+// Frustum reports whether an AABB given by its min and max corners is
+// at least partially visible.
+type Frustum interface {
+	Intersects(min, max [3]float64) bool
+}
+
+// Visible returns the populated leaves whose bounding box intersects
+// f, so render cost tracks visible surface rather than total volume.
+func (o *Octree) Visible(f Frustum) []Leaf {
+	var leaves []Leaf
+	o.root.visible(0, 0, 0, o.size, f, &leaves)
+	return leaves
+}
+
+func (n *node) visible(cx, cy, cz, size float64, f Frustum, out *[]Leaf) {
+	half := size * 0.5
+	min := [3]float64{cx - half, cy - half, cz - half}
+	max := [3]float64{cx + half, cy + half, cz + half}
+	if !f.Intersects(min, max) {
+		return
+	}
+	if n.children[0] == nil {
+		if n.mat != Empty {
+			*out = append(*out, Leaf{cx, cy, cz, size, n.mat})
+		}
+		return
+	}
+	quart := size * 0.25
+	for i := 0; i < cmax; i++ {
+		px := float64((i>>2)&1)*2 - 1
+		py := float64((i>>1)&1)*2 - 1
+		pz := float64(i&1)*2 - 1
+		n.children[i].visible(cx+px*quart, cy+py*quart, cz+pz*quart, half, f, out)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright © 2013-2014 Galvanized Logic Inc.
+// Use is governed by a BSD-style license found in the LICENSE file.
+
+package voxel_test
+
+import (
+	"testing"
+
+	"github.com/gazed/vu"
+	"github.com/gazed/vu/voxel"
+)
+
+// fakeRole is a minimal vu.Role that just records what was set.
+type fakeRole struct{ mesh, material string }
+
+func (r *fakeRole) SetMesh(name string) vu.Role                  { r.mesh = name; return r }
+func (r *fakeRole) SetMaterial(name string) vu.Role              { r.material = name; return r }
+func (r *fakeRole) SetLightLocation(x, y, z float64) vu.Role     { return r }
+func (r *fakeRole) SetLightColour(red, grn, blu float64) vu.Role { return r }
+func (r *fakeRole) SetSubdivisions(n int) vu.Role                { return r }
+
+// fakePart is a minimal vu.Part that records its role and counts how
+// many times SetLocation is called, so tests can assert that MountInto
+// leaves untouched parts alone.
+type fakePart struct {
+	children []*fakePart
+	role     *fakeRole
+	locCalls int
+}
+
+func (p *fakePart) AddPart() vu.Part {
+	c := &fakePart{}
+	p.children = append(p.children, c)
+	return c
+}
+func (p *fakePart) RemPart(rem vu.Part) {
+	c, ok := rem.(*fakePart)
+	if !ok {
+		return
+	}
+	for i, child := range p.children {
+		if child == c {
+			p.children = append(p.children[:i], p.children[i+1:]...)
+			return
+		}
+	}
+}
+func (p *fakePart) SetLocation(x, y, z float64) vu.Part { p.locCalls++; return p }
+func (p *fakePart) SetRotation(x, y, z, w float64) vu.Part { return p }
+func (p *fakePart) SetScale(x, y, z float64) vu.Part    { return p }
+func (p *fakePart) Spin(x, y, z float64)                {}
+func (p *fakePart) SetRole(name string) vu.Role {
+	p.role = &fakeRole{}
+	return p.role
+}
+func (p *fakePart) Role() vu.Role                          { return p.role }
+func (p *fakePart) SetParentKeepTransform(newParent vu.Part) {}
+func (p *fakePart) ClearParentKeepTransform()                {}
+
+func TestOctreeMountAppliesAppearance(t *testing.T) {
+	o := voxel.New(2, 1)
+	o.SetAppearance(1, voxel.Appearance{Mesh: "cube", Material: "blue"})
+	o.Set(0, 0, 0, 1)
+
+	root := &fakePart{}
+	o.MountInto(root)
+
+	if len(root.children) != 1 {
+		t.Fatalf("got %d children, want 1 populated leaf", len(root.children))
+	}
+	leaf := root.children[0]
+	if leaf.role == nil || leaf.role.mesh != "cube" || leaf.role.material != "blue" {
+		t.Fatalf("leaf role = %+v, want mesh=cube material=blue", leaf.role)
+	}
+}
+
+func TestOctreeMountIsLazy(t *testing.T) {
+	o := voxel.New(2, 1)
+	o.Set(0, 0, 0, 1)
+
+	root := &fakePart{}
+	o.MountInto(root)
+	leaf := root.children[0]
+	if leaf.locCalls != 1 {
+		t.Fatalf("locCalls = %d after first mount, want 1", leaf.locCalls)
+	}
+
+	// Editing an unrelated cell must not re-touch a leaf whose subtree
+	// hasn't changed.
+	o.Set(1, 1, 1, 2)
+	o.MountInto(root)
+	if leaf.locCalls != 1 {
+		t.Fatalf("locCalls = %d after unrelated edit, want unchanged at 1", leaf.locCalls)
+	}
+
+	// Editing the leaf's own cell does touch it again.
+	o.Set(0, 0, 0, 2)
+	o.MountInto(root)
+	if leaf.locCalls != 2 {
+		t.Fatalf("locCalls = %d after editing its own cell, want 2", leaf.locCalls)
+	}
+}
+
+func TestCellCoordInvertsCellPath(t *testing.T) {
+	const depth = 2
+	n := 1
+	for i := 0; i < depth; i++ {
+		n *= 8
+	}
+	o := voxel.New(2, depth)
+	for i := 0; i < n; i++ {
+		x, y, z := voxel.CellCoord(i, depth)
+		o.Set(x, y, z, voxel.Material(i%255+1))
+	}
+	root := &fakePart{}
+	o.MountInto(root)
+	// Every cell got a distinct material, so none collapse: the tree
+	// should mount exactly one leaf Part per cell.
+	var countLeaves func(p *fakePart) int
+	countLeaves = func(p *fakePart) int {
+		if len(p.children) == 0 {
+			return 1
+		}
+		total := 0
+		for _, c := range p.children {
+			total += countLeaves(c)
+		}
+		return total
+	}
+	if got := countLeaves(root); got != n {
+		t.Fatalf("mounted %d leaves, want %d", got, n)
+	}
+}